@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// colorMode mirrors the --color flag: auto follows isatty/NO_COLOR, always
+// and never force the corresponding behaviour.
+type colorMode int
+
+const (
+	colorAuto colorMode = iota
+	colorAlways
+	colorNever
+)
+
+var color = colorAuto
+
+func parseColorMode(str string) (colorMode, error) {
+	switch str {
+	case "", "always":
+		return colorAlways, nil
+	case "auto":
+		return colorAuto, nil
+	case "never":
+		return colorNever, nil
+	}
+	return colorAuto, fmt.Errorf("invalid color mode: %s", str)
+}
+
+func colorEnabled() bool {
+	switch color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func paint(code, str string) string {
+	if !colorEnabled() {
+		return str
+	}
+	return "\033[" + code + "m" + str + "\033[0m"
+}
+
+func bold(str string) string    { return paint("1", str) }
+func red(str string) string     { return paint("31", str) }
+func green(str string) string   { return paint("32", str) }
+func cyan(str string) string    { return paint("36", str) }
+func magenta(str string) string { return paint("1;35", str) }
+func blue(str string) string    { return paint("1;34", str) }
+
+// arrow prefixes str with the "::" marker used for progress messages.
+func arrow(str string) string {
+	return blue("::") + " " + str
+}
+
+// warn prints a message with the "==> WARNING:" prefix in white-on-yellow.
+func warn(format string, args ...any) {
+	fmt.Println(paint("37;43", "==> WARNING:") + " " + fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds ~/.config/aur/config.json. CLI flags always win over these
+// values; they only set the starting defaults.
+type Config struct {
+	Editor                string `json:"Editor"`
+	Devel                 bool   `json:"Devel"`
+	NoEdit                bool   `json:"NoEdit"`
+	RemoveMake            bool   `json:"RemoveMake"`
+	RequestTimeout        int    `json:"RequestTimeout"`
+	MaxConcurrentRequests int    `json:"MaxConcurrentRequests"`
+	SortMode              string `json:"SortMode"`
+	PkgDest               string `json:"PkgDest"`
+	BuildDir              string `json:"BuildDir"`
+}
+
+func defaultConfig() Config {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	return Config{
+		Editor:                editor,
+		RequestTimeout:        30,
+		MaxConcurrentRequests: 4,
+		SortMode:              "bottomup",
+	}
+}
+
+// promptEditor asks for an editor command when neither $VISUAL nor $EDITOR
+// is set, like yay does, falling back to vim if left blank. Called lazily
+// from editPKGBUILD, right before an editor is actually run, so operations
+// that never touch a PKGBUILD (search, remove, clean, --help) never block
+// on stdin.
+func promptEditor() string {
+	fmt.Print(arrow("No $VISUAL or $EDITOR set, enter an editor command [vim]: "))
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "vim"
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "vim"
+	}
+	return line
+}
+
+func configPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, dbname, "config.json")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", dbname, "config.json")
+}
+
+func loadConfig() Config {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(configPath())
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.MaxConcurrentRequests < 1 {
+		cfg.MaxConcurrentRequests = 1
+	}
+	return cfg
+}
+
+var config = loadConfig()
+
+func pkgDest() string {
+	if config.PkgDest != "" {
+		return config.PkgDest
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cache", dbname)
+}
+
+func buildDir() string {
+	if config.BuildDir != "" {
+		return config.BuildDir
+	}
+	return os.TempDir()
+}
@@ -5,8 +5,11 @@ package main
 import "C"
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -15,30 +18,322 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	syncpkg "sync"
 	"time"
 )
 
 var dbname = "aur"
 var handle = C.alpm_initialize(C.CString("/"), C.CString("/var/lib/pacman/"), nil)
 var db = C.alpm_register_syncdb(handle, C.CString(dbname), 0)
-var pkgdest = filepath.Join(os.Getenv("HOME"), ".cache", dbname)
+var syncdbs = repoDbs()
+var pkgdest = pkgDest()
 var dbpath = filepath.Join(pkgdest, dbname+".db.tar.gz")
-var re = regexp.MustCompile(`.*/(.*)-(.*?-.*?)-.*?\.pkg\.tar\.zst`)
+var machine = unameMachine()
 var force = false
-var devel = false
-var noedit = false
+var devel = config.Devel
+var noedit = config.NoEdit
+var menu = false
 
 type Package struct {
-	Description string  `json:"Description"`
-	Maintainer  string  `json:"Maintainer"`
-	Name        string  `json:"Name"`
-	NumVotes    int     `json:"NumVotes"`
-	OutOfDate   int64   `json:"OutOfDate"`
-	PackageBase string  `json:"PackageBase"`
-	Popularity  float64 `json:"Popularity"`
-	Version     string  `json:"Version"`
-	OldVersion  string
+	Description  string   `json:"Description"`
+	Maintainer   string   `json:"Maintainer"`
+	Name         string   `json:"Name"`
+	NumVotes     int      `json:"NumVotes"`
+	OutOfDate    int64    `json:"OutOfDate"`
+	PackageBase  string   `json:"PackageBase"`
+	Popularity   float64  `json:"Popularity"`
+	Version      string   `json:"Version"`
+	Depends      []string `json:"Depends"`
+	MakeDepends  []string `json:"MakeDepends"`
+	CheckDepends []string `json:"CheckDepends"`
+	OldVersion   string
+}
+
+// depOrder is one build step: all the pkgnames produced by a single
+// PackageBase, built together and added to the repo in one repo-add call.
+type depOrder struct {
+	Base string
+	Pkgs []Package
+}
+
+// repoDbs registers every repo declared in pacman.conf as a sync db, so
+// alpmFindSatisfier can tell repo dependencies apart from AUR ones.
+func repoDbs() []*C.alpm_db_t {
+	f, err := os.Open("/etc/pacman.conf")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	section := regexp.MustCompile(`^\[(.+)\]$`)
+	dbs := []*C.alpm_db_t{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := section.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil || m[1] == "options" || m[1] == dbname {
+			continue
+		}
+		dbs = append(dbs, C.alpm_register_syncdb(handle, C.CString(m[1]), 0))
+	}
+	return dbs
+}
+
+// depName strips a version constraint (eg. "foo>=1.2") off a depends entry.
+func depName(dep string) string {
+	if i := strings.IndexAny(dep, "<>="); i >= 0 {
+		return dep[:i]
+	}
+	return dep
+}
+
+func satisfiedLocally(dep string) bool {
+	return C.alpm_find_satisfier(C.alpm_db_get_pkgcache(C.alpm_get_localdb(handle)), C.CString(dep)) != nil
+}
+
+func satisfiedByRepo(dep string) bool {
+	for _, sdb := range syncdbs {
+		if C.alpm_find_satisfier(C.alpm_db_get_pkgcache(sdb), C.CString(dep)) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveDeps recursively resolves Depends/MakeDepends/CheckDepends for seed
+// against the AUR RPC and alpm, and returns the AUR bases in build order
+// (leaves first) plus the set of repo packages that must be installed
+// alongside them.
+func resolveDeps(seed []Package) ([]depOrder, []string) {
+	nodes := make(map[string][]Package)
+	nameBase := make(map[string]string)
+	repoSet := make(map[string]struct{})
+	queued := make(map[string]bool)
+	queue := append([]Package{}, seed...)
+	for _, p := range seed {
+		queued[p.Name] = true
+		nameBase[p.Name] = p.PackageBase
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		nodes[p.PackageBase] = append(nodes[p.PackageBase], p)
+		deps := append(append(append([]string{}, p.Depends...), p.MakeDepends...), p.CheckDepends...)
+		need := []string{}
+		for _, dep := range deps {
+			name := depName(dep)
+			if name == p.PackageBase || queued[name] {
+				continue
+			}
+			if satisfiedLocally(dep) {
+				continue
+			}
+			if satisfiedByRepo(dep) {
+				repoSet[name] = struct{}{}
+				continue
+			}
+			queued[name] = true
+			need = append(need, name)
+		}
+		if len(need) == 0 {
+			continue
+		}
+		found := fetch(need)
+		if len(found) != len(need) {
+			missing := make(map[string]bool)
+			for _, n := range need {
+				missing[n] = true
+			}
+			for _, f := range found {
+				delete(missing, f.Name)
+			}
+			for n := range missing {
+				log.Fatal("could not resolve dependency: ", n)
+			}
+		}
+		for _, f := range found {
+			nameBase[f.Name] = f.PackageBase
+			queue = append(queue, f)
+		}
+	}
+	deps := make(map[string][]string)
+	for base, pkgs := range nodes {
+		seen := make(map[string]bool)
+		for _, p := range pkgs {
+			all := append(append(append([]string{}, p.Depends...), p.MakeDepends...), p.CheckDepends...)
+			for _, dep := range all {
+				depBase, ok := nameBase[depName(dep)]
+				if !ok || depBase == base || seen[depBase] {
+					continue
+				}
+				seen[depBase] = true
+				deps[base] = append(deps[base], depBase)
+			}
+		}
+	}
+	order := make([]depOrder, 0, len(nodes))
+	for _, base := range topoSort(nodes, deps) {
+		order = append(order, depOrder{Base: base, Pkgs: nodes[base]})
+	}
+	repoDeps := make([]string, 0, len(repoSet))
+	for name := range repoSet {
+		repoDeps = append(repoDeps, name)
+	}
+	sort.Strings(repoDeps)
+	return order, repoDeps
+}
+
+// topoSort orders bases so that each base's dependencies come before it,
+// failing with the offending cycle if one is found.
+func topoSort(nodes map[string][]Package, deps map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	path := []string{}
+	order := []string{}
+	var visit func(base string)
+	visit = func(base string) {
+		switch state[base] {
+		case visited:
+			return
+		case visiting:
+			i := 0
+			for path[i] != base {
+				i++
+			}
+			log.Fatal("dependency cycle detected: ", strings.Join(append(path[i:], base), " -> "))
+		}
+		state[base] = visiting
+		path = append(path, base)
+		for _, dep := range deps[base] {
+			visit(dep)
+		}
+		path = path[:len(path)-1]
+		state[base] = visited
+		order = append(order, base)
+	}
+	bases := make([]string, 0, len(nodes))
+	for base := range nodes {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+	for _, base := range bases {
+		visit(base)
+	}
+	return order
+}
+
+// printTargets prints the Repo/Aur target summary shown before a sync
+// prompt, as a header-and-rows table matching the one prepare() prints for
+// outdated packages.
+func printTargets(repoDeps []string, aurBases []string) {
+	printTable := func(label string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		nlen := 0
+		clen := len(fmt.Sprintf("%d", len(names)))
+		for _, n := range names {
+			nlen = max(nlen, len(n))
+		}
+		nlen = max(nlen, len(label)+3+clen)
+		fmt.Println()
+		fmt.Println(bold(fmt.Sprintf("%s (%d)", label, len(names))))
+		fmt.Println()
+		for _, n := range names {
+			fmt.Printf("%-*s\n", nlen, n)
+		}
+	}
+	printTable("Repo", repoDeps)
+	printTable("Aur", aurBases)
+}
+
+// srcinfo holds the fields of a .SRCINFO we care about. Split packages
+// repeat pkgname under one pkgbase. depends/makedepends/checkdepends are
+// deliberately not modeled here: resolveDeps resolves the whole tree
+// up front from the AUR RPC info response, before any base is cloned, so a
+// locally checked-out .SRCINFO is never available at the point dependency
+// resolution needs it.
+type srcinfo struct {
+	Pkgbase  string
+	Pkgnames []string
+	Pkgver   string
+	Pkgrel   string
+	Epoch    string
+	Arch     []string
+}
+
+func (s *srcinfo) version() string {
+	v := s.Pkgver + "-" + s.Pkgrel
+	if s.Epoch != "" {
+		v = s.Epoch + ":" + v
+	}
+	return v
+}
+
+func (s *srcinfo) supports(machine string) bool {
+	for _, a := range s.Arch {
+		if a == "any" || a == machine {
+			return true
+		}
+	}
+	return len(s.Arch) == 0
+}
+
+func parseSrcinfo(data []byte) *srcinfo {
+	info := &srcinfo{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		switch {
+		case key == "pkgbase":
+			info.Pkgbase = val
+		case key == "pkgname":
+			info.Pkgnames = append(info.Pkgnames, val)
+		case key == "pkgver":
+			info.Pkgver = val
+		case key == "pkgrel":
+			info.Pkgrel = val
+		case key == "epoch":
+			info.Epoch = val
+		case key == "arch":
+			info.Arch = append(info.Arch, val)
+		}
+	}
+	return info
+}
+
+func readSrcinfo(src string) *srcinfo {
+	data, err := os.ReadFile(filepath.Join(src, ".SRCINFO"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return parseSrcinfo(data)
+}
+
+func unameMachine() string {
+	output, err := exec.Command("uname", "-m").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// checkArch warns and returns false if base can't be built on this machine.
+func checkArch(base string, info *srcinfo) bool {
+	if info.supports(machine) {
+		return true
+	}
+	warn("%s does not support %s, skipping", base, machine)
+	return false
 }
 
 type Result struct {
@@ -46,10 +341,34 @@ type Result struct {
 	Results     []Package `json:"results"`
 }
 
+const (
+	chunkSize   = 150
+	maxAttempts = 4
+)
+
+var maxWorkers = config.MaxConcurrentRequests
+var httpClient = &http.Client{Timeout: time.Duration(config.RequestTimeout) * time.Second}
+
+// get fetches u, retrying transient errors (and 5xx/429 responses) with
+// exponential backoff.
 func get(u *url.URL) []Package {
-	res, err := http.Get(u.String())
-	if err != nil {
-		log.Fatal(err)
+	var res *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		res, err = httpClient.Get(u.String())
+		if err == nil && res.StatusCode < http.StatusInternalServerError && res.StatusCode != http.StatusTooManyRequests {
+			break
+		}
+		if err == nil {
+			res.Body.Close()
+		}
+		if attempt >= maxAttempts-1 {
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Fatal("AUR RPC request failed: ", res.Status)
+		}
+		time.Sleep(time.Duration(1<<attempt) * time.Second)
 	}
 	var result Result
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
@@ -58,14 +377,44 @@ func get(u *url.URL) []Package {
 	return result.Results
 }
 
+// chunk splits names into slices of at most size entries.
+func chunk(names []string, size int) [][]string {
+	chunks := [][]string{}
+	for size < len(names) {
+		names, chunks = names[size:], append(chunks, names[0:size:size])
+	}
+	return append(chunks, names)
+}
+
+// fetch looks up names on the AUR RPC, split into chunkSize-sized batches
+// dispatched across a bounded worker pool so the request URL never grows
+// past the RPC's length limit.
 func fetch(names []string) []Package {
-	fmt.Println("\033[1;34m::\033[39m Fetching packages...\033[0m")
-	u, err := url.Parse("https://aur.archlinux.org/rpc/v5/info")
-	if err != nil {
-		log.Fatal(err)
+	fmt.Println(arrow("Fetching packages..."))
+	chunks := chunk(names, chunkSize)
+	results := make([][]Package, len(chunks))
+	sem := make(chan struct{}, maxWorkers)
+	var wg syncpkg.WaitGroup
+	for i, batch := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, batch []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			u, err := url.Parse("https://aur.archlinux.org/rpc/v5/info")
+			if err != nil {
+				log.Fatal(err)
+			}
+			u.RawQuery = url.Values{"arg[]": batch}.Encode()
+			results[i] = get(u)
+		}(i, batch)
+	}
+	wg.Wait()
+	pkgs := []Package{}
+	for _, r := range results {
+		pkgs = append(pkgs, r...)
 	}
-	u.RawQuery = url.Values{"arg[]": names}.Encode()
-	return get(u)
+	return pkgs
 }
 
 func remove(names []string) {
@@ -85,25 +434,114 @@ func search(str string) {
 		log.Fatal(err)
 	}
 	pkgs := get(u.JoinPath(str))
-	sort.Slice(pkgs, func(i, j int) bool {
+	lessRelevant := func(i, j int) bool {
 		if pkgs[i].Popularity == pkgs[j].Popularity {
 			return pkgs[i].NumVotes < pkgs[j].NumVotes
 		}
 		return pkgs[i].Popularity < pkgs[j].Popularity
-	})
-	for _, p := range pkgs {
-		fmt.Printf("\033[1;35maur/\033[39m%s \033[32m%s\033[39m \033[36m[%d %f]\033[0m", p.Name, p.Version, p.NumVotes, p.Popularity)
+	}
+	if config.SortMode == "topdown" {
+		sort.Slice(pkgs, func(i, j int) bool { return lessRelevant(j, i) })
+	} else {
+		sort.Slice(pkgs, lessRelevant)
+	}
+	for i, p := range pkgs {
+		if menu {
+			fmt.Printf("%s ", bold(fmt.Sprintf("%d", i+1)))
+		}
+		fmt.Printf("%s%s %s %s", magenta("aur/"), p.Name, green(p.Version), cyan(fmt.Sprintf("[%d %f]", p.NumVotes, p.Popularity)))
 		if p.OutOfDate > 0 {
 			date := time.Unix(p.OutOfDate, 0).Format(time.DateOnly)
-			fmt.Printf(" \033[31m%s\033[39m", date)
+			fmt.Printf(" %s", red(date))
 		}
 		fmt.Println("\n   ", p.Description)
 	}
+	if !menu || len(pkgs) == 0 {
+		return
+	}
+	sel := promptSelection("Packages to install (eg: 1 2 3, 1-3 or ^4)", len(pkgs))
+	names := make([]string, 0, len(sel))
+	for _, i := range sel {
+		names = append(names, pkgs[i-1].Name)
+	}
+	if len(names) > 0 {
+		sync(names)
+	}
+}
+
+// parseSelection parses a selection expression like "1 2 3", "1-3" or
+// "1-5 ^3" into the sorted, de-duplicated list of indices it selects.
+// Ranges are inclusive; a leading ^ excludes an index or range instead of
+// including it, letting later tokens carve exclusions out of earlier ones.
+func parseSelection(input string, max int) ([]int, error) {
+	included := make(map[int]bool)
+	excluded := make(map[int]bool)
+	for _, tok := range strings.Fields(input) {
+		exclude := strings.HasPrefix(tok, "^")
+		tok = strings.TrimPrefix(tok, "^")
+		lo, hi, err := parseRange(tok)
+		if err != nil {
+			return nil, err
+		}
+		if lo < 1 || hi > max {
+			return nil, fmt.Errorf("selection %q out of range (1-%d)", tok, max)
+		}
+		for i := lo; i <= hi; i++ {
+			if exclude {
+				excluded[i] = true
+			} else {
+				included[i] = true
+			}
+		}
+	}
+	sel := []int{}
+	for i := 1; i <= max; i++ {
+		if included[i] && !excluded[i] {
+			sel = append(sel, i)
+		}
+	}
+	return sel, nil
+}
+
+func parseRange(tok string) (int, int, error) {
+	if a, b, ok := strings.Cut(tok, "-"); ok {
+		lo, err := strconv.Atoi(a)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid selection: %q", tok)
+		}
+		hi, err := strconv.Atoi(b)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid selection: %q", tok)
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		return lo, hi, nil
+	}
+	n, err := strconv.Atoi(tok)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid selection: %q", tok)
+	}
+	return n, n, nil
+}
+
+func promptSelection(str string, max int) []int {
+	fmt.Printf("%s: ", arrow(str))
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatal(err)
+	}
+	sel, err := parseSelection(strings.TrimSpace(line), max)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return sel
 }
 
 func makepkg(base string, arg ...string) *exec.Cmd {
 	cmd := exec.Command("makepkg", arg...)
-	cmd.Env = append(cmd.Environ(), "PKGDEST="+pkgdest, "BUILDDIR="+os.TempDir())
+	cmd.Env = append(cmd.Environ(), "PKGDEST="+pkgdest, "BUILDDIR="+buildDir())
 	cmd.Dir = filepath.Join(pkgdest, base)
 	return cmd
 }
@@ -113,15 +551,15 @@ func VCSVersion(base string) map[string]string {
 	if err := cmd.Run(); err != nil {
 		log.Fatal(err)
 	}
-	cmd = makepkg(base, "--packagelist")
+	cmd = makepkg(base, "--printsrcinfo")
 	output, err := cmd.Output()
 	if err != nil {
 		log.Fatal(err)
 	}
+	info := parseSrcinfo(output)
 	version := make(map[string]string)
-	for _, str := range strings.Split(string(output), " ") {
-		match := re.FindStringSubmatch(str)
-		version[match[1]] = match[2]
+	for _, name := range info.Pkgnames {
+		version[name] = info.version()
 	}
 	return version
 }
@@ -147,10 +585,10 @@ func prepare(names []string) []Package {
 	for _, p := range pkgs {
 		if p.OutOfDate != 0 {
 			date := time.Unix(p.OutOfDate, 0).Format(time.DateOnly)
-			fmt.Printf("\033[1;33m==> WARNING:\033[39m %s is flagged out of date (%s)\033[0m\n", p.Name, date)
+			warn("%s is flagged out of date (%s)", p.Name, date)
 		}
 		if p.Maintainer == "" {
-			fmt.Printf("\033[1;33m==> WARNING:\033[39m %s is orphan\033[0m\n", p.Name)
+			warn("%s is orphan", p.Name)
 		}
 	}
 	outdated := []Package{}
@@ -178,14 +616,15 @@ func prepare(names []string) []Package {
 		if vlen != 0 {
 			vlen = max(vlen, 11)
 		}
-		fmt.Println("\033[1m")
-		fmt.Printf("%-*s  ", nlen, fmt.Sprintf("Package (%d)", len(outdated)))
+		header := fmt.Sprintf("%-*s  ", nlen, fmt.Sprintf("Package (%d)", len(outdated)))
 		if vlen != 0 {
-			fmt.Printf("Old Version  %*s", vlen, "New Version")
+			header += fmt.Sprintf("Old Version  %*s", vlen, "New Version")
 		} else {
-			fmt.Print("New Version")
+			header += "New Version"
 		}
-		fmt.Println("\033[0m\n")
+		fmt.Println()
+		fmt.Println(bold(header))
+		fmt.Println()
 		sort.Slice(outdated, func(i, j int) bool { return outdated[i].Name < outdated[j].Name })
 		for _, p := range outdated {
 			if vlen != 0 {
@@ -227,7 +666,7 @@ func build(base string) {
 }
 
 func prompt(str string) bool {
-	fmt.Printf("\033[1;34m::\033[39m %s [Y/n]\033[0m ", str)
+	fmt.Printf("%s [Y/n] ", arrow(str))
 	var ans string
 	fmt.Scanln(&ans)
 	switch ans {
@@ -239,7 +678,10 @@ func prompt(str string) bool {
 
 func editPKGBUILD(src string) {
 	if !noedit && prompt("Edit PKGBUILD?") {
-		cmd := exec.Command("vim", filepath.Join(src, "PKGBUILD"))
+		if config.Editor == "" {
+			config.Editor = promptEditor()
+		}
+		cmd := exec.Command(config.Editor, filepath.Join(src, "PKGBUILD"))
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
 		if err := cmd.Run(); err != nil {
@@ -248,18 +690,55 @@ func editPKGBUILD(src string) {
 	}
 }
 
+// buildOrder installs repoDeps, then walks order (already topologically
+// sorted by resolveDeps) cloning/updating, checking arch support, and
+// building each base. prep fetches the base's source into src (clone for
+// sync, fetch+merge for update) before it is inspected and built.
+func buildOrder(label string, order []depOrder, repoDeps []string, prep func(base, src string)) {
+	if len(repoDeps) > 0 {
+		arg := append([]string{"-S", "--asdeps", "--needed"}, repoDeps...)
+		cmd := exec.Command("pacman", arg...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, d := range order {
+		fmt.Println(arrow(label + ": " + d.Base))
+		src := filepath.Join(pkgdest, d.Base)
+		prep(d.Base, src)
+		if !checkArch(d.Base, readSrcinfo(src)) {
+			continue
+		}
+		editPKGBUILD(src)
+		build(d.Base)
+	}
+	if config.RemoveMake && len(repoDeps) > 0 {
+		arg := append([]string{"-Rns", "--noconfirm"}, repoDeps...)
+		cmd := exec.Command("pacman", arg...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			warn("could not remove build dependencies: %v", err)
+		}
+	}
+}
+
 func sync(names []string) {
 	pkgs := prepare(names)
+	order, repoDeps := resolveDeps(pkgs)
+	bases := make([]string, len(order))
+	for i, d := range order {
+		bases[i] = d.Base
+	}
+	printTargets(repoDeps, bases)
 	if !prompt("Proceed with synchronising?") {
 		os.Exit(1)
 	}
-	bases := make(map[string]struct{})
-	for _, p := range pkgs {
-		bases[p.PackageBase] = struct{}{}
-	}
-	for base := range bases {
-		fmt.Printf("\033[1;34m::\033[39m Syncing: %s\n", base)
-		src := filepath.Join(pkgdest, base)
+	buildOrder("Syncing", order, repoDeps, func(base, src string) {
 		if _, err := os.Stat(src); err != nil {
 			url := "https://aur.archlinux.org/" + base + ".git"
 			cmd := exec.Command("git", "clone", url, src)
@@ -267,9 +746,7 @@ func sync(names []string) {
 				log.Fatal(err)
 			}
 		}
-		editPKGBUILD(src)
-		build(base)
-	}
+	})
 }
 
 func git(src string, arg ...string) *exec.Cmd {
@@ -289,16 +766,16 @@ func update() {
 		cache = cache.next
 	}
 	pkgs := prepare(names)
+	order, repoDeps := resolveDeps(pkgs)
+	bases := make([]string, len(order))
+	for i, d := range order {
+		bases[i] = d.Base
+	}
+	printTargets(repoDeps, bases)
 	if !prompt("Proceed with updating?") {
 		os.Exit(1)
 	}
-	bases := make(map[string]struct{})
-	for _, p := range pkgs {
-		bases[p.PackageBase] = struct{}{}
-	}
-	for base := range bases {
-		fmt.Printf("\033[1;34m::\033[39m Updating: %s\n", base)
-		src := filepath.Join(pkgdest, base)
+	buildOrder("Updating", order, repoDeps, func(base, src string) {
 		cmd := git(src, "fetch", "--quiet")
 		if err := cmd.Run(); err != nil {
 			log.Fatal(err)
@@ -319,9 +796,7 @@ func update() {
 		if err := cmd.Run(); err != nil {
 			log.Fatal(err)
 		}
-		editPKGBUILD(src)
-		build(base)
-	}
+	})
 }
 
 func clean() {
@@ -363,17 +838,76 @@ func clean() {
 	}
 }
 
+// cleandeps finds AUR packages pulled in as dependencies that nothing else
+// requires anymore and offers to remove them, like `pacman -Qtdq | pacman -Rns -`.
+func cleandeps() {
+	localdb := C.alpm_get_localdb(handle)
+	cache := C.alpm_db_get_pkgcache(localdb)
+	orphans := []Package{}
+	for cache != nil {
+		pkg := (*C.alpm_pkg_t)(cache.data)
+		cache = cache.next
+		if C.alpm_pkg_get_reason(pkg) != C.ALPM_PKG_REASON_DEPEND {
+			continue
+		}
+		if C.alpm_pkg_compute_requiredby(pkg) != nil {
+			continue
+		}
+		name := C.GoString(C.alpm_pkg_get_name(pkg))
+		if C.alpm_db_get_pkg(db, C.CString(name)) == nil {
+			continue
+		}
+		orphans = append(orphans, Package{Name: name, Version: C.GoString(C.alpm_pkg_get_version(pkg))})
+	}
+	if len(orphans) == 0 {
+		fmt.Println("There is nothing to do")
+		os.Exit(0)
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+	nlen := 0
+	clen := len(fmt.Sprintf("%d", len(orphans)))
+	for _, p := range orphans {
+		nlen = max(nlen, len(p.Name))
+	}
+	nlen = max(nlen, 10+clen)
+	fmt.Println()
+	fmt.Println(bold(fmt.Sprintf("%-*s  Version", nlen, fmt.Sprintf("Package (%d)", len(orphans)))))
+	fmt.Println()
+	for _, p := range orphans {
+		fmt.Printf("%-*s  %s\n", nlen, p.Name, p.Version)
+	}
+	fmt.Println()
+	if !prompt("Remove unneeded packages?") {
+		os.Exit(1)
+	}
+	names := make([]string, len(orphans))
+	for i, p := range orphans {
+		names[i] = p.Name
+	}
+	arg := append([]string{"-Rns", "--noconfirm"}, names...)
+	cmd := exec.Command("pacman", arg...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func usage() {
 	fmt.Println(`usage: aur <operation>
 operations:
     clean
+    cleandeps
     remove [package(s)]
     search [string]
     sync   [options] [package(s)]
     update [options]
 options:
+    --color=auto|always|never  control colored output
     --devel   check development packages during update
     --force   always sync packages
+    --menu    select search results to install
     --noedit  don't edit PKGBUILD`)
 	os.Exit(0)
 }
@@ -382,13 +916,22 @@ func parser() (string, []string) {
 	args := []string{}
 	for _, a := range os.Args[1:] {
 		if strings.HasPrefix(a, "-") {
-			switch a[2:] {
+			name, value, _ := strings.Cut(a[2:], "=")
+			switch name {
 			case "help":
 				usage()
+			case "color":
+				mode, err := parseColorMode(value)
+				if err != nil {
+					log.Fatal(err)
+				}
+				color = mode
 			case "devel":
 				devel = true
 			case "force":
 				force = true
+			case "menu":
+				menu = true
 			case "noedit":
 				noedit = true
 			default:
@@ -409,6 +952,8 @@ func main() {
 	switch op {
 	case "clean":
 		clean()
+	case "cleandeps":
+		cleandeps()
 	case "remove":
 		remove(args)
 	case "search":
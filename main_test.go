@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	cases := []struct {
+		input string
+		max   int
+		want  []int
+	}{
+		{"1 2 3", 5, []int{1, 2, 3}},
+		{"2-5", 5, []int{2, 3, 4, 5}},
+		{"1-3 2-4", 5, []int{1, 2, 3, 4}},
+		{"1-5 ^3", 5, []int{1, 2, 4, 5}},
+		{"1-5 ^2-3", 5, []int{1, 4, 5}},
+		{"^2 1-3", 5, []int{1, 3}},
+	}
+	for _, c := range cases {
+		got, err := parseSelection(c.input, c.max)
+		if err != nil {
+			t.Errorf("parseSelection(%q, %d) returned error: %v", c.input, c.max, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parseSelection(%q, %d) = %v, want %v", c.input, c.max, got, c.want)
+		}
+	}
+}
+
+func TestParseSelectionOutOfBounds(t *testing.T) {
+	cases := []struct {
+		input string
+		max   int
+	}{
+		{"6", 5},
+		{"0", 5},
+		{"1-6", 5},
+		{"abc", 5},
+	}
+	for _, c := range cases {
+		if _, err := parseSelection(c.input, c.max); err == nil {
+			t.Errorf("parseSelection(%q, %d) expected an error, got none", c.input, c.max)
+		}
+	}
+}